@@ -0,0 +1,191 @@
+package notes
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// MetadataFormat selects how a note's metadata (category, tags, created
+// time, and any extra fields) is read and written. Existing notes using the
+// original `- Category:` / `- Tags:` / `- Created:` bullets keep working
+// under BulletsFormat, which is the zero value so Config defaults to it.
+type MetadataFormat int
+
+const (
+	BulletsFormat MetadataFormat = iota
+	YAMLFormat
+	TOMLFormat
+)
+
+// ParseMetadataFormat converts a Config value like "bullets", "yaml" or
+// "toml" into a MetadataFormat.
+func ParseMetadataFormat(s string) (MetadataFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "bullets":
+		return BulletsFormat, nil
+	case "yaml":
+		return YAMLFormat, nil
+	case "toml":
+		return TOMLFormat, nil
+	default:
+		return BulletsFormat, errors.Errorf("Unknown metadata format '%s'. Must be one of 'bullets', 'yaml', 'toml'", s)
+	}
+}
+
+const frontMatterTimeFormat = time.RFC3339
+
+// yamlFence and tomlFence are the fence lines that mark the start and end of
+// a front-matter block, per the Jekyll/Hugo convention.
+const (
+	yamlFence = "---"
+	tomlFence = "+++"
+)
+
+// sniffFrontMatterFormat reports which fence, if any, the given first
+// non-empty line of a note opens.
+func sniffFrontMatterFormat(line string) (MetadataFormat, bool) {
+	switch strings.TrimSpace(line) {
+	case yamlFence:
+		return YAMLFormat, true
+	case tomlFence:
+		return TOMLFormat, true
+	default:
+		return BulletsFormat, false
+	}
+}
+
+// readFrontMatter reads lines from s up to (and consuming) the closing fence
+// matching format, and parses the metadata found between the fences into
+// note. s must have already consumed the opening fence line.
+func readFrontMatter(s *bufio.Scanner, format MetadataFormat, note *Note) error {
+	fence := yamlFence
+	if format == TOMLFormat {
+		fence = tomlFence
+	}
+
+	var body bytes.Buffer
+	closed := false
+	for s.Scan() {
+		line := s.Text()
+		if strings.TrimSpace(line) == fence {
+			closed = true
+			break
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	if !closed {
+		return errors.New("Front matter block is not closed with a matching fence")
+	}
+
+	raw := map[string]interface{}{}
+	var err error
+	if format == YAMLFormat {
+		err = yaml.Unmarshal(body.Bytes(), &raw)
+	} else {
+		err = toml.Unmarshal(body.Bytes(), &raw)
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot parse front matter block")
+	}
+
+	return applyFrontMatter(raw, note)
+}
+
+func applyFrontMatter(raw map[string]interface{}, note *Note) error {
+	note.Extra = map[string]interface{}{}
+
+	for k, v := range raw {
+		switch strings.ToLower(k) {
+		case "category":
+			s, ok := v.(string)
+			if !ok {
+				return errors.Errorf("'category' in front matter must be a string, got %v", v)
+			}
+			note.Category = s
+		case "tags":
+			tags, err := toStringSlice(v)
+			if err != nil {
+				return errors.Wrap(err, "'tags' in front matter must be a list of strings")
+			}
+			note.Tags = tags
+		case "created":
+			s, ok := v.(string)
+			if !ok {
+				return errors.Errorf("'created' in front matter must be a string, got %v", v)
+			}
+			t, err := time.Parse(frontMatterTimeFormat, s)
+			if err != nil {
+				return errors.Wrapf(err, "Cannot parse 'created' in front matter as RFC3339 format: %s", s)
+			}
+			note.Created = t
+		default:
+			note.Extra[k] = v
+		}
+	}
+
+	if note.Tags == nil {
+		note.Tags = []string{}
+	}
+
+	return nil
+}
+
+func toStringSlice(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("expected a list, got %v", v)
+	}
+	ss := make([]string, 0, len(items))
+	for _, i := range items {
+		s, ok := i.(string)
+		if !ok {
+			return nil, errors.Errorf("expected a string in list, got %v", i)
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+// writeFrontMatter renders note's metadata and Extra fields as a front
+// matter block in the given format, fenced as YAML or TOML expects.
+func writeFrontMatter(b *bytes.Buffer, format MetadataFormat, note *Note) error {
+	data := map[string]interface{}{}
+	for k, v := range note.Extra {
+		data[k] = v
+	}
+	data["category"] = note.Category
+	data["tags"] = note.Tags
+	data["created"] = note.Created.Format(frontMatterTimeFormat)
+
+	fence := yamlFence
+	if format == TOMLFormat {
+		fence = tomlFence
+	}
+
+	b.WriteString(fence + "\n")
+	switch format {
+	case YAMLFormat:
+		out, err := yaml.Marshal(data)
+		if err != nil {
+			return errors.Wrap(err, "Cannot render YAML front matter")
+		}
+		b.Write(out)
+	case TOMLFormat:
+		enc := toml.NewEncoder(b)
+		if err := enc.Encode(data); err != nil {
+			return errors.Wrap(err, "Cannot render TOML front matter")
+		}
+	default:
+		return errors.Errorf("Cannot write front matter for metadata format %v", format)
+	}
+	b.WriteString(fence + "\n\n")
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package notes
+
+import (
+	"github.com/spf13/afero"
+)
+
+// Fs is the filesystem every Note reads and writes through. It is stored on
+// Config so a single process can point different notes at different
+// backends (a real directory, an in-memory tree for tests, an encrypted or
+// remote overlay in the future) without any of note.go knowing the
+// difference.
+type Fs = afero.Fs
+
+// OsFs is the default Fs, backed by the real filesystem. Config uses this
+// unless a test or caller overrides it.
+func OsFs() Fs {
+	return afero.NewOsFs()
+}
+
+// MemFs returns an in-memory Fs suitable for hermetic unit tests and dry-run
+// modes: nothing written to it touches disk.
+func MemFs() Fs {
+	return afero.NewMemMapFs()
+}
+
+// BasePathFs roots fs at base, so paths given to the returned Fs are
+// resolved relative to base. This lets one process manage multiple note
+// roots, each as its own Fs rooted at a different directory of the same
+// backing Fs.
+func BasePathFs(fs Fs, base string) Fs {
+	return afero.NewBasePathFs(fs, base)
+}
@@ -0,0 +1,44 @@
+package notes
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestMemFsIsHermetic(t *testing.T) {
+	fs := MemFs()
+
+	if err := afero.WriteFile(fs, "/notes/work/hello.md", []byte("hi"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	got, err := afero.ReadFile(fs, "/notes/work/hello.md")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "hi" {
+		t.Errorf("content = %q, want %q", got, "hi")
+	}
+
+	if ok, _ := afero.Exists(OsFs(), "/notes/work/hello.md"); ok {
+		t.Errorf("MemFs write leaked onto the real filesystem")
+	}
+}
+
+func TestBasePathFsRootsPaths(t *testing.T) {
+	mem := MemFs()
+	rootA := BasePathFs(mem, "/a")
+	rootB := BasePathFs(mem, "/b")
+
+	if err := afero.WriteFile(rootA, "note.md", []byte("a"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if ok, _ := afero.Exists(rootB, "note.md"); ok {
+		t.Errorf("note written under rootA is visible under rootB")
+	}
+	if ok, _ := afero.Exists(mem, "/a/note.md"); !ok {
+		t.Errorf("note written under rootA did not land at /a/note.md on the underlying Fs")
+	}
+}
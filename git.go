@@ -0,0 +1,140 @@
+package notes
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Git wraps the `git` binary so notes under Config.HomePath can be
+// committed, synced and inspected with their history, the same way
+// WalkNotes already treats `.git` as part of the furniture by skipping it.
+type Git struct {
+	Config *Config
+}
+
+// NewGit returns a Git bound to cfg.HomePath.
+func NewGit(cfg *Config) *Git {
+	return &Git{Config: cfg}
+}
+
+func (g *Git) run(args ...string) error {
+	c := exec.Command("git", args...)
+	c.Dir = g.Config.HomePath
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Stdin = os.Stdin
+	return errors.Wrapf(c.Run(), "Git %s failed", strings.Join(args, " "))
+}
+
+// Add stages a note's file, given its path relative to Config.HomePath.
+func (g *Git) Add(relPath string) error {
+	return g.run("add", relPath)
+}
+
+// Commit commits whatever is currently staged with the given message. If
+// nothing was actually staged (e.g. an editor rewrote a note without
+// changing its content), `git commit` exits non-zero but there's nothing to
+// report as a failure, so that case is treated as a no-op.
+func (g *Git) Commit(message string) error {
+	c := exec.Command("git", "commit", "-m", message)
+	c.Dir = g.Config.HomePath
+	out, err := c.CombinedOutput()
+	os.Stdout.Write(out)
+	if err != nil && !strings.Contains(string(out), "nothing to commit") {
+		return errors.Wrap(err, "Git commit failed")
+	}
+	return nil
+}
+
+// Push pushes to Config.GitRemote, defaulting to "origin" when unset.
+func (g *Git) Push() error {
+	remote := g.Config.GitRemote
+	if remote == "" {
+		remote = "origin"
+	}
+	return g.run("push", remote)
+}
+
+// Sync rebases onto the remote and pushes, for a `notes sync` command.
+func (g *Git) Sync() error {
+	if err := g.run("pull", "--rebase"); err != nil {
+		return err
+	}
+	return g.Push()
+}
+
+// Log shows the revision history of a single note, for a `notes log <note>`
+// command.
+func (g *Git) Log(note *Note) error {
+	return g.run("log", "-p", "--", note.RelFilePath())
+}
+
+// commitMessage renders Config.GitCommitTemplate (or a default) against the
+// note being committed and the action that triggered it ("Add" or "Edit").
+func (g *Git) commitMessage(note *Note, action string) (string, error) {
+	text := g.Config.GitCommitTemplate
+	if text == "" {
+		text = "{{.Action}} note: {{.Title}}"
+	}
+
+	tmpl, err := template.New("git-commit-message").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot parse Config.GitCommitTemplate")
+	}
+
+	data := struct {
+		Action   string
+		Title    string
+		Category string
+		Tags     []string
+	}{action, note.Title, note.Category, note.Tags}
+
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrap(err, "Cannot render git commit message")
+	}
+	return b.String(), nil
+}
+
+// AutoCommit stages and commits note if Config.GitAutoCommit is enabled, and
+// pushes too when Config.GitAutoPush is also enabled. It's a no-op when
+// GitAutoCommit is off, so callers can call it unconditionally after a note
+// is created or edited.
+func (g *Git) AutoCommit(note *Note, action string) error {
+	if !g.Config.GitAutoCommit {
+		return nil
+	}
+
+	if err := g.Add(note.RelFilePath()); err != nil {
+		return err
+	}
+
+	msg, err := g.commitMessage(note, action)
+	if err != nil {
+		return err
+	}
+	if err := g.Commit(msg); err != nil {
+		return err
+	}
+
+	if g.Config.GitAutoPush {
+		return g.Push()
+	}
+	return nil
+}
+
+// Sync runs `git pull --rebase && git push` over cfg.HomePath, for a `notes
+// sync` command.
+func Sync(cfg *Config) error {
+	return NewGit(cfg).Sync()
+}
+
+// ShowLog prints a note's git history, for a `notes log <note>` command.
+func ShowLog(note *Note) error {
+	return NewGit(note.Config).Log(note)
+}
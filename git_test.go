@@ -0,0 +1,167 @@
+package notes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeGitScript is a shell script standing in for the real `git` binary. It
+// appends every invocation (one line, args joined by spaces) to $FAKE_GIT_LOG.
+// For `commit` it then writes $FAKE_GIT_COMMIT_OUTPUT (if set) to stdout and
+// exits with $FAKE_GIT_COMMIT_EXIT (default 0); every other command exits 0.
+const fakeGitScript = `#!/bin/sh
+echo "$*" >> "$FAKE_GIT_LOG"
+case "$1" in
+commit)
+	if [ -n "$FAKE_GIT_COMMIT_OUTPUT" ]; then
+		echo "$FAKE_GIT_COMMIT_OUTPUT"
+	fi
+	exit "${FAKE_GIT_COMMIT_EXIT:-0}"
+	;;
+esac
+exit 0
+`
+
+// withFakeGit puts a fake `git` binary at the front of PATH for the duration
+// of the test, logging every invocation to a file it returns the path of.
+func withFakeGit(t *testing.T) (logPath string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "git")
+	if err := ioutil.WriteFile(scriptPath, []byte(fakeGitScript), 0755); err != nil {
+		t.Fatalf("WriteFile fake git: %v", err)
+	}
+
+	logFile := filepath.Join(dir, "invocations.log")
+	if err := ioutil.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("WriteFile fake git log: %v", err)
+	}
+
+	origPath := os.Getenv("PATH")
+	origLog := os.Getenv("FAKE_GIT_LOG")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+origPath)
+	os.Setenv("FAKE_GIT_LOG", logFile)
+	t.Cleanup(func() {
+		os.Setenv("PATH", origPath)
+		os.Setenv("FAKE_GIT_LOG", origLog)
+	})
+
+	return logFile
+}
+
+func readInvocations(t *testing.T, logPath string) []string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("ReadFile invocations log: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
+
+func TestGitAddAndPush(t *testing.T) {
+	logPath := withFakeGit(t)
+	g := NewGit(&Config{HomePath: t.TempDir()})
+
+	if err := g.Add("work/note.md"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := g.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	got := readInvocations(t, logPath)
+	want := []string{"add work/note.md", "push origin"}
+	if len(got) != len(want) {
+		t.Fatalf("invocations = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("invocation[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGitCommitNothingToCommitIsNotAnError(t *testing.T) {
+	withFakeGit(t)
+	os.Setenv("FAKE_GIT_COMMIT_OUTPUT", "nothing to commit, working tree clean")
+	os.Setenv("FAKE_GIT_COMMIT_EXIT", "1")
+	defer os.Unsetenv("FAKE_GIT_COMMIT_OUTPUT")
+	defer os.Unsetenv("FAKE_GIT_COMMIT_EXIT")
+
+	g := NewGit(&Config{HomePath: t.TempDir()})
+	if err := g.Commit("Add note: Foo"); err != nil {
+		t.Errorf("Commit with nothing staged = %v, want nil", err)
+	}
+}
+
+func TestGitCommitRealFailureIsReported(t *testing.T) {
+	withFakeGit(t)
+	os.Setenv("FAKE_GIT_COMMIT_OUTPUT", "fatal: not a git repository")
+	os.Setenv("FAKE_GIT_COMMIT_EXIT", "128")
+	defer os.Unsetenv("FAKE_GIT_COMMIT_OUTPUT")
+	defer os.Unsetenv("FAKE_GIT_COMMIT_EXIT")
+
+	g := NewGit(&Config{HomePath: t.TempDir()})
+	err := g.Commit("Add note: Foo")
+	if err == nil {
+		t.Fatal("Commit returned no error, want one reporting the failure")
+	}
+	if !strings.Contains(err.Error(), "Git commit failed") {
+		t.Errorf("error = %q, want it to mention %q", err.Error(), "Git commit failed")
+	}
+}
+
+func TestGitAutoCommitIsNoOpWhenDisabled(t *testing.T) {
+	logPath := withFakeGit(t)
+
+	cfg := &Config{HomePath: t.TempDir()}
+	note, err := NewNote("work", "", "note.md", "Note", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+
+	if err := NewGit(cfg).AutoCommit(note, "Add"); err != nil {
+		t.Fatalf("AutoCommit: %v", err)
+	}
+
+	if got := readInvocations(t, logPath); len(got) != 0 {
+		t.Errorf("invocations = %v, want none since GitAutoCommit is disabled", got)
+	}
+}
+
+func TestGitAutoCommitStagesAndCommits(t *testing.T) {
+	logPath := withFakeGit(t)
+
+	cfg := &Config{HomePath: t.TempDir(), GitAutoCommit: true}
+	note, err := NewNote("work", "", "note.md", "Note", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+
+	if err := NewGit(cfg).AutoCommit(note, "Add"); err != nil {
+		t.Fatalf("AutoCommit: %v", err)
+	}
+
+	got := readInvocations(t, logPath)
+	if len(got) != 2 {
+		t.Fatalf("invocations = %v, want 2 (add, commit)", got)
+	}
+	if !strings.HasPrefix(got[0], "add "+note.RelFilePath()) {
+		t.Errorf("invocation[0] = %q, want it to add %q", got[0], note.RelFilePath())
+	}
+	if !strings.HasPrefix(got[1], "commit -m") {
+		t.Errorf("invocation[1] = %q, want it to commit", got[1])
+	}
+	if !strings.Contains(got[1], "Add note: Note") {
+		t.Errorf("invocation[1] = %q, want it to mention the rendered commit message", got[1])
+	}
+}
@@ -0,0 +1,393 @@
+package notes
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// dbExecutor is the subset of *sql.DB and *sql.Tx that Index needs, so its
+// query helpers work the same whether or not they're running inside Commit.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// IndexedPath is the on-disk metadata recorded for a single note the last
+// time it was indexed. It lets a caller decide whether a note needs to be
+// re-parsed without reading its content.
+type IndexedPath struct {
+	Path    string
+	ModTime int64
+	Hash    string
+}
+
+// Index is a persistent SQLite-backed index of every note under
+// Config.HomePath: an FTS5 table for full-text search plus a collections
+// table for tags/categories, so queries like `notes list --tag foo
+// --category bar --match "some phrase"` don't need a filesystem walk.
+type Index struct {
+	Config *Config
+	db     *sql.DB
+	exec   dbExecutor
+}
+
+const indexSchema = `
+CREATE TABLE IF NOT EXISTS notes (
+	path     TEXT PRIMARY KEY,
+	title    TEXT NOT NULL,
+	category TEXT NOT NULL,
+	created  TEXT NOT NULL,
+	hash     TEXT NOT NULL,
+	mtime    INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS collections (
+	path  TEXT NOT NULL,
+	kind  TEXT NOT NULL,
+	value TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS collections_value_idx ON collections (kind, value);
+CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+	path UNINDEXED,
+	title,
+	tags,
+	body
+);
+`
+
+const sqliteTimeFormat = "2006-01-02T15:04:05Z07:00"
+
+// OpenIndex opens (creating if necessary) the SQLite index file at
+// Config.IndexPath and brings its schema up to date.
+//
+// This requires github.com/mattn/go-sqlite3 to have compiled in FTS5
+// support, which only happens when the package is built (and tested) with
+// the `sqlite_fts5` build tag:
+//
+//	go build -tags sqlite_fts5 ./...
+//	go test -tags sqlite_fts5 ./...
+//
+// Without the tag, creating the `notes_fts` virtual table fails and
+// OpenIndex returns an error wrapping "no such module: fts5".
+func OpenIndex(cfg *Config) (*Index, error) {
+	p := cfg.IndexPath
+	if p == "" {
+		p = filepath.Join(cfg.HomePath, ".notes-index.sqlite3")
+	}
+
+	db, err := sql.Open("sqlite3", p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot open note index at '%s'", p)
+	}
+
+	if _, err := db.Exec(indexSchema); err != nil {
+		db.Close()
+		if strings.Contains(err.Error(), "fts5") {
+			return nil, errors.Wrap(err, "Cannot prepare note index schema: this package must be built (and tested) with 'go build -tags sqlite_fts5 ./...' for github.com/mattn/go-sqlite3 to support FTS5")
+		}
+		return nil, errors.Wrap(err, "Cannot prepare note index schema")
+	}
+
+	return &Index{Config: cfg, db: db, exec: db}, nil
+}
+
+func (idx *Index) Close() error {
+	return idx.db.Close()
+}
+
+// Add inserts or refreshes a note's row, collections and FTS content.
+func (idx *Index) Add(note *Note) error {
+	return idx.Commit(func(tx Index) error {
+		return tx.upsert(note)
+	})
+}
+
+// Update is an alias for Add: both fully replace whatever was previously
+// indexed for the note.
+func (idx *Index) Update(note *Note) error {
+	return idx.Add(note)
+}
+
+// Remove deletes a note, identified by its path relative to Config.HomePath,
+// from the index.
+func (idx *Index) Remove(path string) error {
+	return idx.Commit(func(tx Index) error {
+		if _, err := tx.exec.Exec(`DELETE FROM notes WHERE path = ?`, path); err != nil {
+			return err
+		}
+		if _, err := tx.exec.Exec(`DELETE FROM collections WHERE path = ?`, path); err != nil {
+			return err
+		}
+		_, err := tx.exec.Exec(`DELETE FROM notes_fts WHERE path = ?`, path)
+		return err
+	})
+}
+
+// Commit runs fn against a single SQLite transaction, so a batch of
+// Add/Update/Remove calls either all land or all roll back together.
+func (idx *Index) Commit(fn func(Index) error) error {
+	// Already inside a Commit: join the enclosing transaction.
+	if _, inTx := idx.exec.(*sql.Tx); inTx {
+		return fn(*idx)
+	}
+
+	tx, err := idx.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Cannot begin note index transaction")
+	}
+
+	if err := fn(Index{Config: idx.Config, db: idx.db, exec: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "Cannot commit note index transaction")
+}
+
+func (idx *Index) upsert(note *Note) error {
+	hash, err := hashNoteFile(note)
+	if err != nil {
+		return err
+	}
+
+	body, err := note.ReadBodyN(1 << 20)
+	if err != nil {
+		return err
+	}
+
+	info, err := note.Config.Fs.Stat(note.FilePath())
+	if err != nil {
+		return errors.Wrapf(err, "Cannot stat note file '%s'", note.RelFilePath())
+	}
+
+	path := note.RelFilePath()
+
+	if _, err := idx.exec.Exec(
+		`INSERT INTO notes (path, title, category, created, hash, mtime) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(path) DO UPDATE SET title=excluded.title, category=excluded.category,
+		 created=excluded.created, hash=excluded.hash, mtime=excluded.mtime`,
+		path, note.Title, note.Category, note.Created.Format(sqliteTimeFormat), hash, info.ModTime().Unix(),
+	); err != nil {
+		return errors.Wrapf(err, "Cannot upsert note '%s' into index", path)
+	}
+
+	if _, err := idx.exec.Exec(`DELETE FROM collections WHERE path = ?`, path); err != nil {
+		return err
+	}
+	for _, t := range note.Tags {
+		if _, err := idx.exec.Exec(`INSERT INTO collections (path, kind, value) VALUES (?, 'tag', ?)`, path, t); err != nil {
+			return err
+		}
+	}
+	if _, err := idx.exec.Exec(`INSERT INTO collections (path, kind, value) VALUES (?, 'category', ?)`, path, note.Category); err != nil {
+		return err
+	}
+
+	if _, err := idx.exec.Exec(`DELETE FROM notes_fts WHERE path = ?`, path); err != nil {
+		return err
+	}
+	_, err = idx.exec.Exec(
+		`INSERT INTO notes_fts (path, title, tags, body) VALUES (?, ?, ?, ?)`,
+		path, note.Title, strings.Join(note.Tags, " "), body,
+	)
+	return errors.Wrapf(err, "Cannot index body of note '%s'", path)
+}
+
+// IndexedPaths returns the path/mtime/hash metadata currently stored in the
+// index over a channel, so a caller can reconcile it against the filesystem
+// and decide which notes actually need to be re-parsed. The channel is
+// materialized eagerly and fully buffered before it's returned, so a caller
+// that stops consuming early can't leak a goroutine or an open result set.
+func (idx *Index) IndexedPaths() (<-chan IndexedPath, error) {
+	rows, err := idx.db.Query(`SELECT path, mtime, hash FROM notes`)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot query indexed paths")
+	}
+	defer rows.Close()
+
+	var all []IndexedPath
+	for rows.Next() {
+		var p IndexedPath
+		if err := rows.Scan(&p.Path, &p.ModTime, &p.Hash); err != nil {
+			return nil, errors.Wrap(err, "Cannot scan indexed path row")
+		}
+		all = append(all, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Cannot read indexed paths")
+	}
+
+	ch := make(chan IndexedPath, len(all))
+	for _, p := range all {
+		ch <- p
+	}
+	close(ch)
+	return ch, nil
+}
+
+// SearchResult is a single match returned by Index.Search, ordered by
+// relevance (best match first).
+type SearchResult struct {
+	Path     string
+	Title    string
+	Category string
+	Tags     []string
+	Rank     float64
+}
+
+// Search runs an FTS5 MATCH query over indexed notes' titles, tags and
+// bodies, ranked by BM25 with title and tags weighted above body text.
+// Passing "" for tag or category skips that filter, so `notes list --tag foo
+// --category bar --match "some phrase"` is a single indexed query instead of
+// a filesystem walk.
+func (idx *Index) Search(term, tag, category string) ([]SearchResult, error) {
+	query := `
+		SELECT n.path, n.title, n.category, bm25(notes_fts, 1.0, 10.0, 5.0, 1.0) AS rank
+		FROM notes_fts
+		JOIN notes n ON n.path = notes_fts.path
+		WHERE notes_fts MATCH ?`
+	args := []interface{}{term}
+
+	if category != "" {
+		query += " AND n.category = ?"
+		args = append(args, category)
+	}
+	if tag != "" {
+		query += " AND n.path IN (SELECT path FROM collections WHERE kind = 'tag' AND value = ?)"
+		args = append(args, tag)
+	}
+	query += " ORDER BY rank"
+
+	rows, err := idx.db.Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot search notes index for '%s'", term)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Path, &r.Title, &r.Category, &r.Rank); err != nil {
+			return nil, errors.Wrap(err, "Cannot read search result row")
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "Cannot read search results")
+	}
+
+	for i := range results {
+		tags, err := idx.tagsOf(results[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		results[i].Tags = tags
+	}
+
+	return results, nil
+}
+
+func (idx *Index) tagsOf(path string) ([]string, error) {
+	rows, err := idx.db.Query(`SELECT value FROM collections WHERE path = ? AND kind = 'tag'`, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Cannot load tags for '%s'", path)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// Sync walks path and re-indexes every note unless both its mtime and its
+// content hash match what is stored in the index, and removes rows for notes
+// that disappeared. Every note is still loaded and parsed by WalkNotes during
+// the walk; the mtime/hash check only skips re-indexing.
+func (idx *Index) Sync(path string) error {
+	known := map[string]IndexedPath{}
+	ch, err := idx.IndexedPaths()
+	if err != nil {
+		return err
+	}
+	for p := range ch {
+		known[p.Path] = p
+	}
+
+	seen := map[string]bool{}
+	if err := WalkNotes(path, idx.Config, func(p string, note *Note) error {
+		seen[note.RelFilePath()] = true
+
+		info, err := idx.Config.Fs.Stat(p)
+		if err != nil {
+			return err
+		}
+
+		if prev, ok := known[note.RelFilePath()]; ok && prev.ModTime == info.ModTime().Unix() {
+			hash, err := hashNoteFile(note)
+			if err != nil {
+				return err
+			}
+			if hash == prev.Hash {
+				return nil
+			}
+		}
+
+		return idx.Update(note)
+	}); err != nil {
+		return err
+	}
+
+	for p := range known {
+		if !seen[p] {
+			if err := idx.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hashNoteFile(note *Note) (string, error) {
+	b, err := afero.ReadFile(note.Config.Fs, note.FilePath())
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot read note file '%s' to hash it", note.RelFilePath())
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// indexNoteIfEnabled adds note to the index when Config.IndexEnabled is set,
+// so Note.Create, Note.Save and Note.Open can keep the index up to date
+// without every caller having to manage an *Index itself. It's a no-op
+// otherwise, just like Git.AutoCommit is a no-op without GitAutoCommit.
+//
+// Config.IndexEnabled must default to false: the index depends on FTS5
+// support that only exists when this package is built with the
+// sqlite_fts5 tag (see OpenIndex), so turning it on should be an explicit,
+// informed opt-in rather than something a caller gets for free.
+func indexNoteIfEnabled(note *Note) error {
+	if !note.Config.IndexEnabled {
+		return nil
+	}
+
+	idx, err := OpenIndex(note.Config)
+	if err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	return idx.Add(note)
+}
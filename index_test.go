@@ -0,0 +1,158 @@
+//go:build sqlite_fts5
+
+package notes
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestIndexConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	return &Config{
+		HomePath:  dir,
+		Fs:        OsFs(), // sqlite3 needs a real file on disk
+		IndexPath: filepath.Join(dir, "notes-index.sqlite3"),
+	}
+}
+
+func TestIndexAddAndSearch(t *testing.T) {
+	cfg := newTestIndexConfig(t)
+
+	idx, err := OpenIndex(cfg)
+	if err != nil {
+		t.Fatalf("OpenIndex: %v", err)
+	}
+	defer idx.Close()
+
+	note, err := NewNote("work", "urgent, billing", "invoice.md", "Invoice Reminder", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := idx.Add(note); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	results, err := idx.Search("Invoice", "", "")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("len(results) = %d, want 1", len(results))
+	}
+	if results[0].Title != "Invoice Reminder" {
+		t.Errorf("Title = %q, want %q", results[0].Title, "Invoice Reminder")
+	}
+
+	if results, err := idx.Search("Invoice", "urgent", ""); err != nil || len(results) != 1 {
+		t.Errorf("Search with matching tag filter = %v, %v; want 1 result", results, err)
+	}
+	if results, err := idx.Search("Invoice", "nonexistent", ""); err != nil || len(results) != 0 {
+		t.Errorf("Search with non-matching tag filter = %v, %v; want 0 results", results, err)
+	}
+}
+
+// TestSearchRanksTitleAndTagAboveBody asserts rank order, not just hit
+// presence: a title match must outrank a tag match, which must outrank a
+// body-only match for the same term.
+func TestSearchRanksTitleAndTagAboveBody(t *testing.T) {
+	cfg := newTestIndexConfig(t)
+
+	idx, err := OpenIndex(cfg)
+	if err != nil {
+		t.Fatalf("OpenIndex: %v", err)
+	}
+	defer idx.Close()
+
+	titleNote, err := NewNote("work", "", "title-match.md", "Quarterly Report", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := titleNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tagNote, err := NewNote("work", "quarterly", "tag-match.md", "Unrelated Title", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := tagNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	bodyNote, err := NewNote("work", "", "body-match.md", "Other Title", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := bodyNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	body := "Other Title\n===========\n- Category: work\n- Tags: \n- Created: " +
+		bodyNote.Created.Format(time.RFC3339) + "\n\nThis note mentions quarterly planning.\n"
+	if err := bodyNote.Save([]byte(body)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	for _, n := range []*Note{titleNote, tagNote, bodyNote} {
+		if err := idx.Add(n); err != nil {
+			t.Fatalf("Add(%s): %v", n.File, err)
+		}
+	}
+
+	results, err := idx.Search("quarterly", "", "")
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("len(results) = %d, want 3", len(results))
+	}
+
+	gotOrder := []string{results[0].Path, results[1].Path, results[2].Path}
+	wantOrder := []string{titleNote.RelFilePath(), tagNote.RelFilePath(), bodyNote.RelFilePath()}
+	for i := range wantOrder {
+		if gotOrder[i] != wantOrder[i] {
+			t.Errorf("result[%d] = %q, want %q (order: %v)", i, gotOrder[i], wantOrder[i], gotOrder)
+		}
+	}
+}
+
+func TestIndexSyncRemovesDeletedNotes(t *testing.T) {
+	cfg := newTestIndexConfig(t)
+
+	idx, err := OpenIndex(cfg)
+	if err != nil {
+		t.Fatalf("OpenIndex: %v", err)
+	}
+	defer idx.Close()
+
+	note, err := NewNote("work", "", "temp.md", "Temporary", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if err := idx.Sync(cfg.HomePath); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if results, err := idx.Search("Temporary", "", ""); err != nil || len(results) != 1 {
+		t.Fatalf("Search after first sync = %v, %v; want 1 result", results, err)
+	}
+
+	if err := cfg.Fs.Remove(note.FilePath()); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := idx.Sync(cfg.HomePath); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if results, err := idx.Search("Temporary", "", ""); err != nil || len(results) != 0 {
+		t.Errorf("Search after note removed = %v, %v; want 0 results", results, err)
+	}
+}
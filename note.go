@@ -5,8 +5,8 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"io"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -26,6 +26,11 @@ type Note struct {
 	Created  time.Time
 	File     string
 	Title    string
+	// Extra holds any front-matter fields beyond Category, Tags and Created
+	// (e.g. Aliases, Deadline, Status). It's only populated for notes using
+	// YAMLFormat or TOMLFormat metadata; bullet-metadata notes can't
+	// represent arbitrary fields, so it's nil for them.
+	Extra map[string]interface{}
 }
 
 func (note *Note) DirPath() string {
@@ -43,6 +48,13 @@ func (note *Note) RelFilePath() string {
 func (note *Note) Create() error {
 	var b bytes.Buffer
 
+	format := note.Config.MetadataFormat
+	if format != BulletsFormat {
+		if err := writeFrontMatter(&b, format, note); err != nil {
+			return err
+		}
+	}
+
 	// Write title
 	title := note.Title
 	if title == "" {
@@ -51,39 +63,143 @@ func (note *Note) Create() error {
 	b.WriteString(title + "\n")
 	b.WriteString(strings.Repeat("=", len(title)) + "\n")
 
-	// Write metadata
-	fmt.Fprintf(&b, "- Category: %s\n", note.Category)
-	fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(note.Tags, ", "))
-	fmt.Fprintf(&b, "- Created: %s\n\n", note.Created.Format(time.RFC3339))
+	if format == BulletsFormat {
+		// Write metadata
+		fmt.Fprintf(&b, "- Category: %s\n", note.Category)
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(note.Tags, ", "))
+		fmt.Fprintf(&b, "- Created: %s\n\n", note.Created.Format(time.RFC3339))
+	} else {
+		b.WriteString("\n")
+	}
 
+	fs := note.Config.Fs
 	d := note.DirPath()
-	if err := os.MkdirAll(d, 0755); err != nil {
+	if err := fs.MkdirAll(d, 0755); err != nil {
 		return errors.Wrapf(err, "Could not create category directory '%s'", d)
 	}
 
 	p := filepath.Join(d, note.File)
-	if _, err := os.Stat(p); err == nil {
-		return errors.Errorf("Cannot create new note since file '%s' already exists. Please edit it", note.RelFilePath())
+	if _, err := fs.Stat(p); err == nil {
+		return errors.WithStack(&NoteExistsError{RelPath: note.RelFilePath()})
+	}
+
+	if err := note.WriteAtomic(b.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	if err := indexNoteIfEnabled(note); err != nil {
+		return err
+	}
+
+	return NewGit(note.Config).AutoCommit(note, "Add")
+}
+
+// NoteExistsError is returned by Note.Create when the destination file
+// already exists, so callers can tell that case apart from an I/O failure.
+type NoteExistsError struct {
+	RelPath string
+}
+
+func (e *NoteExistsError) Error() string {
+	return fmt.Sprintf("Cannot create new note since file '%s' already exists. Please edit it", e.RelPath)
+}
+
+// Save overwrites the note's file with new content. Unlike Create, the note
+// is expected to already exist; it's meant for edits, not first writes.
+func (note *Note) Save(body []byte) error {
+	if err := note.WriteAtomic(body, 0644); err != nil {
+		return err
+	}
+	return indexNoteIfEnabled(note)
+}
+
+// WriteAtomic writes b to the note's file without ever leaving a truncated
+// or partially-written file behind, even if the process is killed or the
+// machine loses power mid-write: it writes to a temp file in the same
+// directory, fsyncs it, renames it over the destination, then fsyncs the
+// directory so the rename itself is durable. This is reused by Create and
+// Save, and is available to future features (import, merge,
+// format-conversion) that need to write a note's content safely.
+func (note *Note) WriteAtomic(b []byte, perm os.FileMode) error {
+	fs := note.Config.Fs
+	d := note.DirPath()
+	p := note.FilePath()
+
+	tmp, err := afero.TempFile(fs, d, "."+note.File+".tmp-*")
+	if err != nil {
+		return errors.Wrapf(err, "Cannot create temporary file in '%s'", d)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = fs.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "Cannot write note contents to temporary file '%s'", tmpPath)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "Cannot fsync temporary file '%s'", tmpPath)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "Cannot close temporary file '%s'", tmpPath)
+	}
+
+	if err := fs.Chmod(tmpPath, perm); err != nil {
+		return errors.Wrapf(err, "Cannot set permissions on temporary file '%s'", tmpPath)
+	}
+	if err := fs.Rename(tmpPath, p); err != nil {
+		return errors.Wrapf(err, "Cannot move temporary file to '%s'", note.RelFilePath())
 	}
 
-	return errors.Wrap(ioutil.WriteFile(p, b.Bytes(), 0644), "Cannot write note to file")
+	return errors.Wrapf(fsyncDir(fs, d), "Cannot fsync directory '%s'", d)
+}
+
+func fsyncDir(fs afero.Fs, dir string) error {
+	d, err := fs.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
 }
 
 func (note *Note) Open() error {
 	if note.Config.EditorPath == "" {
 		return errors.New("Editor is not set. To open note in editor, please set $NOTES_CLI_EDITOR")
 	}
+
+	trackChange := note.Config.GitAutoCommit || note.Config.IndexEnabled
+	var before os.FileInfo
+	if trackChange {
+		before, _ = note.Config.Fs.Stat(note.FilePath())
+	}
+
 	c := exec.Command(note.Config.EditorPath, note.FilePath())
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
 	c.Stdin = os.Stdin
 	c.Dir = note.DirPath()
-	return errors.Wrap(c.Run(), "Editor command did not run successfully")
+	if err := c.Run(); err != nil {
+		return errors.Wrap(err, "Editor command did not run successfully")
+	}
+
+	if !trackChange {
+		return nil
+	}
+	after, err := note.Config.Fs.Stat(note.FilePath())
+	if err != nil || (before != nil && after.ModTime().Equal(before.ModTime())) {
+		return nil
+	}
+
+	if err := indexNoteIfEnabled(note); err != nil {
+		return err
+	}
+	return NewGit(note.Config).AutoCommit(note, "Edit")
 }
 
 func (note *Note) ReadBodyN(maxBytes int64) (string, error) {
 	path := note.FilePath()
-	f, err := os.Open(path)
+	f, err := note.Config.Fs.Open(path)
 	if err != nil {
 		return "", errors.Wrap(err, "Cannot open note file")
 	}
@@ -155,11 +271,11 @@ func NewNote(cat, tags, file, title string, cfg *Config) (*Note, error) {
 	if !strings.HasSuffix(file, ".md") {
 		file += ".md"
 	}
-	return &Note{cfg, cat, ts, time.Now(), file, title}, nil
+	return &Note{Config: cfg, Category: cat, Tags: ts, Created: time.Now(), File: file, Title: title}, nil
 }
 
 func LoadNote(path string, cfg *Config) (*Note, error) {
-	f, err := os.Open(path)
+	f, err := cfg.Fs.Open(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "Cannot open note file")
 	}
@@ -170,9 +286,34 @@ func LoadNote(path string, cfg *Config) (*Note, error) {
 	note.File = filepath.Base(path)
 
 	s := bufio.NewScanner(f)
+
+	line, ok := nextNonEmptyLine(s)
+	if !ok {
+		if err := s.Err(); err != nil {
+			return nil, errors.Wrapf(err, "Cannot read note file '%s'", canonPath(path))
+		}
+		return nil, errors.Errorf("Note file '%s' is empty", canonPath(path))
+	}
+
+	// A YAML or TOML front-matter block, if present, always comes before the
+	// title; fall through to the bullets-style scan below for the rest.
+	if format, hasFrontMatter := sniffFrontMatterFormat(line); hasFrontMatter {
+		if err := readFrontMatter(s, format, note); err != nil {
+			return nil, errors.Wrapf(err, "Cannot read front matter of note '%s'", canonPath(path))
+		}
+		if note.Category != "" {
+			if c := filepath.Base(filepath.Dir(path)); c != note.Category {
+				return nil, errors.Errorf("Category does not match between file path and file content, in path '%s' v.s. in file '%s'", c, note.Category)
+			}
+		}
+		line, ok = nextNonEmptyLine(s)
+		if !ok {
+			return nil, errors.Errorf("No title found in note '%s'. Didn't you use '====' bar for h1 title?", canonPath(path))
+		}
+	}
+
 	titleFound := false
-	for s.Scan() {
-		line := s.Text()
+	for {
 		// First line is title
 		if !titleFound {
 			if reTitleBar.MatchString(line) {
@@ -204,9 +345,13 @@ func LoadNote(path string, cfg *Config) (*Note, error) {
 			}
 			note.Created = t
 		}
-		if note.Category != "" && note.Tags != nil && !note.Created.IsZero() && note.Title != "" {
+		if titleFound && note.Category != "" && note.Tags != nil && !note.Created.IsZero() && note.Title != "" {
 			break
 		}
+		if !s.Scan() {
+			break
+		}
+		line = s.Text()
 	}
 	if err := s.Err(); err != nil {
 		return nil, errors.Wrapf(err, "Cannot read note file '%s'", canonPath(path))
@@ -223,9 +368,20 @@ func LoadNote(path string, cfg *Config) (*Note, error) {
 	return note, nil
 }
 
+// nextNonEmptyLine advances s past any blank lines and returns the next
+// non-empty one, or ok=false once the scanner is exhausted.
+func nextNonEmptyLine(s *bufio.Scanner) (string, bool) {
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) != "" {
+			return s.Text(), true
+		}
+	}
+	return "", false
+}
+
 func WalkNotes(path string, cfg *Config, pred func(path string, note *Note) error) error {
 	return errors.Wrap(
-		filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		afero.Walk(cfg.Fs, path, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,135 @@
+package notes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func newTestConfig() *Config {
+	return &Config{
+		HomePath: "/notes",
+		Fs:       MemFs(),
+	}
+}
+
+func createAndLoad(t *testing.T, cfg *Config, file, title string) *Note {
+	t.Helper()
+
+	note, err := NewNote("work", "a, b", file, title, cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	loaded, err := LoadNote(note.FilePath(), cfg)
+	if err != nil {
+		t.Fatalf("LoadNote: %v", err)
+	}
+	return loaded
+}
+
+func TestCreateAndLoadNoteBullets(t *testing.T) {
+	loaded := createAndLoad(t, newTestConfig(), "hello.md", "Hello")
+
+	if loaded.Title != "Hello" {
+		t.Errorf("Title = %q, want %q", loaded.Title, "Hello")
+	}
+	if loaded.Category != "work" {
+		t.Errorf("Category = %q, want %q", loaded.Category, "work")
+	}
+	if got := strings.Join(loaded.Tags, ","); got != "a,b" {
+		t.Errorf("Tags = %q, want %q", got, "a,b")
+	}
+}
+
+func TestCreateAndLoadNoteYAMLFrontMatter(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MetadataFormat = YAMLFormat
+
+	loaded := createAndLoad(t, cfg, "yaml-note.md", "YAML Note")
+
+	if loaded.Title != "YAML Note" {
+		t.Errorf("Title = %q, want %q", loaded.Title, "YAML Note")
+	}
+	if loaded.Category != "work" {
+		t.Errorf("Category = %q, want %q", loaded.Category, "work")
+	}
+	if got := strings.Join(loaded.Tags, ","); got != "a,b" {
+		t.Errorf("Tags = %q, want %q", got, "a,b")
+	}
+}
+
+func TestCreateAndLoadNoteTOMLFrontMatter(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.MetadataFormat = TOMLFormat
+
+	loaded := createAndLoad(t, cfg, "toml-note.md", "TOML Note")
+
+	if loaded.Title != "TOML Note" {
+		t.Errorf("Title = %q, want %q", loaded.Title, "TOML Note")
+	}
+	if loaded.Category != "work" {
+		t.Errorf("Category = %q, want %q", loaded.Category, "work")
+	}
+}
+
+func TestCreateTwiceReturnsTypedExistsError(t *testing.T) {
+	cfg := newTestConfig()
+
+	note, err := NewNote("work", "", "dup.md", "Dup", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("first Create: %v", err)
+	}
+
+	err = note.Create()
+	if err == nil {
+		t.Fatal("second Create returned no error, want NoteExistsError")
+	}
+	if _, ok := errors.Cause(err).(*NoteExistsError); !ok {
+		t.Errorf("error = %v (%T), want *NoteExistsError", err, errors.Cause(err))
+	}
+}
+
+func TestSaveOverwritesFileAtomically(t *testing.T) {
+	cfg := newTestConfig()
+
+	note, err := NewNote("work", "", "atomic.md", "Atomic", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	newBody := "Atomic\n======\n- Category: work\n- Tags: \n- Created: " +
+		note.Created.Format(time.RFC3339) + "\n\nUpdated body\n"
+	if err := note.Save([]byte(newBody)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	body, err := note.ReadBodyN(1024)
+	if err != nil {
+		t.Fatalf("ReadBodyN: %v", err)
+	}
+	if strings.TrimSpace(body) != "Updated body" {
+		t.Errorf("body = %q, want %q", body, "Updated body")
+	}
+
+	// The file should end up at its final path with no leftover temp files.
+	entries, err := afero.ReadDir(cfg.Fs, note.DirPath())
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != note.File {
+		t.Errorf("directory entries = %v, want only %q", entries, note.File)
+	}
+}
@@ -0,0 +1,100 @@
+package notes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// searchPreviewBytes is how much of a selected note's body is printed when
+// no editor is configured to open it in instead.
+const searchPreviewBytes = 500
+
+type searchHit struct {
+	path string
+	note *Note
+}
+
+// SearchInteractive walks notes under cfg.HomePath, filters them down to the
+// ones whose title, tags or body contain term (case-insensitive substring
+// match), then prompts on stdin for which one to open. Typing 'q' quits
+// without selecting anything; typing a listed number selects that note,
+// opening it with $NOTES_CLI_EDITOR when one is configured, or else printing
+// its title and a short preview of its body. A single rune is read per
+// selection, so only the entries numbered 0 to 9 can be selected.
+func SearchInteractive(cfg *Config, term string) (*Note, error) {
+	term = strings.ToLower(term)
+
+	var hits []searchHit
+	if err := WalkNotes(cfg.HomePath, cfg, func(path string, note *Note) error {
+		if noteMatches(note, term) {
+			hits = append(hits, searchHit{path, note})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(hits) == 0 {
+		fmt.Println("No notes matched")
+		return nil, nil
+	}
+
+	for i, h := range hits {
+		fmt.Printf("%d) %s\n", i, h.note.Title)
+	}
+
+	r := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("> ")
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return nil, errors.Wrap(err, "Cannot read selection from stdin")
+		}
+		if c == 'q' {
+			return nil, nil
+		}
+
+		i, err := strconv.Atoi(string(c))
+		if err != nil || i < 0 || i >= len(hits) {
+			fmt.Println("Please enter a listed number, or 'q' to quit")
+			continue
+		}
+
+		note := hits[i].note
+		if note.Config.EditorPath != "" {
+			return note, note.Open()
+		}
+
+		body, err := note.ReadBodyN(searchPreviewBytes)
+		if err != nil {
+			return note, err
+		}
+		fmt.Println(note.Title)
+		fmt.Println(body)
+		return note, nil
+	}
+}
+
+func noteMatches(note *Note, lowerTerm string) bool {
+	if lowerTerm == "" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(note.Title), lowerTerm) {
+		return true
+	}
+	for _, t := range note.Tags {
+		if strings.Contains(strings.ToLower(t), lowerTerm) {
+			return true
+		}
+	}
+	body, err := note.ReadBodyN(1 << 20)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(body), lowerTerm)
+}
@@ -0,0 +1,86 @@
+package notes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNoteMatchesTitleTagAndBody(t *testing.T) {
+	cfg := newTestConfig()
+
+	titleNote, err := NewNote("work", "", "title-match.md", "Quarterly Review", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := titleNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tagNote, err := NewNote("work", "quarterly", "tag-match.md", "Unrelated Title", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := tagNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	bodyNote, err := NewNote("work", "", "body-match.md", "Another Title", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := bodyNote.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	body := "Another Title\n=============\n- Category: work\n- Tags: \n- Created: " +
+		bodyNote.Created.Format(time.RFC3339) + "\n\nThis note mentions quarterly planning.\n"
+	if err := bodyNote.Save([]byte(body)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	noMatch, err := NewNote("work", "", "no-match.md", "Nothing Here", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := noMatch.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		note *Note
+		want bool
+	}{
+		{"title match", titleNote, true},
+		{"tag match", tagNote, true},
+		{"body match", bodyNote, true},
+		{"no match", noMatch, false},
+	} {
+		loaded, err := LoadNote(tc.note.FilePath(), cfg)
+		if err != nil {
+			t.Fatalf("LoadNote(%s): %v", tc.name, err)
+		}
+		if got := noteMatches(loaded, "quarterly"); got != tc.want {
+			t.Errorf("%s: noteMatches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNoteMatchesEmptyTermMatchesEverything(t *testing.T) {
+	cfg := newTestConfig()
+
+	note, err := NewNote("work", "", "anything.md", "Anything", cfg)
+	if err != nil {
+		t.Fatalf("NewNote: %v", err)
+	}
+	if err := note.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	loaded, err := LoadNote(note.FilePath(), cfg)
+	if err != nil {
+		t.Fatalf("LoadNote: %v", err)
+	}
+	if !noteMatches(loaded, "") {
+		t.Error("noteMatches with an empty term = false, want true")
+	}
+}